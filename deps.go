@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DependencyProvider is an optional interface a Runner can implement to
+// declare its own dependencies instead of (or in addition to) registering
+// them via Container.RegisterWithDeps.
+type DependencyProvider interface {
+	Dependencies() []Runner
+}
+
+// RegisterWithDeps registers service the same way Register does, but also
+// declares that it depends on the given services. All deps must already be
+// registered. StartAll will only start service once every dependency has
+// completed Init and entered Run; StopAll stops service before any of its
+// dependencies.
+func (c *Container) RegisterWithDeps(service Runner, deps ...Runner) {
+	c.Register(service)
+	info := c.serviceByName(serviceName(service))
+
+	for _, dep := range deps {
+		depName := serviceName(dep)
+		if c.serviceByName(depName) == nil {
+			panic(fmt.Sprintf("dependency '%s' of service '%s' is not registered", depName, info.name))
+		}
+		info.deps = append(info.deps, depName)
+	}
+}
+
+// serviceByName returns the registered serviceInfo for name, or nil if no
+// such service was registered.
+func (c *Container) serviceByName(name string) *serviceInfo {
+	for _, s := range c.services {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// dependencyNames returns the names of all services s depends on, combining
+// explicit RegisterWithDeps calls with the DependencyProvider interface.
+func (c *Container) dependencyNames(s *serviceInfo) []string {
+	names := append([]string{}, s.deps...)
+	if dp, ok := s.service.(DependencyProvider); ok {
+		for _, dep := range dp.Dependencies() {
+			names = append(names, serviceName(dep))
+		}
+	}
+	return names
+}
+
+// buildWaves groups the registered services into "waves": each wave only
+// depends on services from earlier waves, and services within a wave are
+// independent of each other and can be started concurrently.
+func (c *Container) buildWaves() ([][]*serviceInfo, error) {
+	indegree := make(map[string]int, len(c.services))
+	dependents := make(map[string][]string)
+	remaining := make(map[string]*serviceInfo, len(c.services))
+
+	for _, s := range c.services {
+		remaining[s.name] = s
+		if _, ok := indegree[s.name]; !ok {
+			indegree[s.name] = 0
+		}
+	}
+	for _, s := range c.services {
+		for _, depName := range c.dependencyNames(s) {
+			if c.serviceByName(depName) == nil {
+				return nil, fmt.Errorf("service '%s' depends on unregistered service '%s'", s.name, depName)
+			}
+			indegree[s.name]++
+			dependents[depName] = append(dependents[depName], s.name)
+		}
+	}
+
+	var waves [][]*serviceInfo
+	for len(remaining) > 0 {
+		var wave []*serviceInfo
+		for name := range remaining {
+			if indegree[name] == 0 {
+				wave = append(wave, remaining[name])
+			}
+		}
+		if len(wave) == 0 {
+			return nil, fmt.Errorf("services: dependency cycle detected among %d remaining service(s)", len(remaining))
+		}
+
+		// Keep wave order deterministic and stable across runs.
+		sort.Slice(wave, func(i, j int) bool {
+			return c.registrationIndex(wave[i].name) < c.registrationIndex(wave[j].name)
+		})
+		waves = append(waves, wave)
+
+		for _, s := range wave {
+			delete(remaining, s.name)
+			delete(indegree, s.name)
+			for _, depName := range dependents[s.name] {
+				indegree[depName]--
+			}
+		}
+	}
+	return waves, nil
+}
+
+// registrationIndex returns the position in which name was registered, used
+// only to keep wave ordering deterministic.
+func (c *Container) registrationIndex(name string) int {
+	for i, s := range c.services {
+		if s.name == name {
+			return i
+		}
+	}
+	return -1
+}
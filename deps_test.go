@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderLog records start/stop events from multiple goroutines so tests can
+// assert on relative ordering without depending on timing.
+type orderLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *orderLog) record(event string) {
+	l.mu.Lock()
+	l.events = append(l.events, event)
+	l.mu.Unlock()
+}
+
+func (l *orderLog) indexOf(event string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, e := range l.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+// orderedService is a Readyer that records a start/stop event and only
+// reports ready once it has recorded its start, so a dependent service's
+// wave is guaranteed not to start beforehand.
+type orderedService struct {
+	name  string
+	log   *orderLog
+	ready *ReadyNotifier
+}
+
+func newOrderedService(name string, log *orderLog) *orderedService {
+	return &orderedService{name: name, log: log, ready: NewReadyNotifier()}
+}
+
+func (o *orderedService) String() string { return o.name }
+
+func (o *orderedService) Ready(ctx context.Context) error {
+	return o.ready.Ready(ctx)
+}
+
+func (o *orderedService) Run(ctx context.Context) error {
+	o.log.record("start:" + o.name)
+	o.ready.SignalReady()
+	<-ctx.Done()
+	o.log.record("stop:" + o.name)
+	return nil
+}
+
+func TestStartStopOrdering(t *testing.T) {
+	log := &orderLog{}
+	c := NewContainer()
+
+	db := newOrderedService("db", log)
+	cache := newOrderedService("cache", log)
+	api := newOrderedService("api", log)
+
+	c.Register(db)
+	c.Register(cache)
+	c.RegisterWithDeps(api, db, cache)
+
+	if err := c.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.StopAll(stopCtx)
+
+	startDB, startCache, startAPI := log.indexOf("start:db"), log.indexOf("start:cache"), log.indexOf("start:api")
+	if startDB < 0 || startCache < 0 || startAPI < 0 {
+		t.Fatalf("not all services recorded a start event: %v", log.events)
+	}
+	if startDB > startAPI || startCache > startAPI {
+		t.Errorf("expected db and cache to start before api, got order %v", log.events)
+	}
+
+	stopDB, stopCache, stopAPI := log.indexOf("stop:db"), log.indexOf("stop:cache"), log.indexOf("stop:api")
+	if stopDB < 0 || stopCache < 0 || stopAPI < 0 {
+		t.Fatalf("not all services recorded a stop event: %v", log.events)
+	}
+	if stopAPI > stopDB || stopAPI > stopCache {
+		t.Errorf("expected api to stop before its dependencies db and cache, got order %v", log.events)
+	}
+}
@@ -0,0 +1,46 @@
+// Package slog adapts the standard library's log/slog to the services.Logger
+// interface, for consumers who don't want a logrus dependency.
+package slog
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/Niondir/go-services"
+)
+
+// Logger adapts a *slog.Logger to services.Logger.
+type Logger struct {
+	log *slog.Logger
+}
+
+// New wraps log into a services.Logger.
+func New(log *slog.Logger) *Logger {
+	return &Logger{log: log}
+}
+
+func (l *Logger) WithField(key string, value interface{}) services.Logger {
+	return &Logger{log: l.log.With(key, value)}
+}
+
+func (l *Logger) WithError(err error) services.Logger {
+	return &Logger{log: l.log.With("error", err)}
+}
+
+func (l *Logger) Debug(args ...interface{}) { l.log.Debug(fmt.Sprint(args...)) }
+func (l *Logger) Info(args ...interface{})  { l.log.Info(fmt.Sprint(args...)) }
+func (l *Logger) Warn(args ...interface{})  { l.log.Warn(fmt.Sprint(args...)) }
+func (l *Logger) Error(args ...interface{}) { l.log.Error(fmt.Sprint(args...)) }
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log.Debug(fmt.Sprintf(format, args...))
+}
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log.Info(fmt.Sprintf(format, args...))
+}
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log.Warn(fmt.Sprintf(format, args...))
+}
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log.Error(fmt.Sprintf(format, args...))
+}
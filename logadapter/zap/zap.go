@@ -0,0 +1,36 @@
+// Package zap adapts go.uber.org/zap to the services.Logger interface, for
+// consumers who don't want a logrus dependency.
+package zap
+
+import (
+	"github.com/Niondir/go-services"
+	"go.uber.org/zap"
+)
+
+// Logger adapts a *zap.SugaredLogger to services.Logger.
+type Logger struct {
+	log *zap.SugaredLogger
+}
+
+// New wraps log into a services.Logger.
+func New(log *zap.SugaredLogger) *Logger {
+	return &Logger{log: log}
+}
+
+func (l *Logger) WithField(key string, value interface{}) services.Logger {
+	return &Logger{log: l.log.With(key, value)}
+}
+
+func (l *Logger) WithError(err error) services.Logger {
+	return &Logger{log: l.log.With("error", err)}
+}
+
+func (l *Logger) Debug(args ...interface{}) { l.log.Debug(args...) }
+func (l *Logger) Info(args ...interface{})  { l.log.Info(args...) }
+func (l *Logger) Warn(args ...interface{})  { l.log.Warn(args...) }
+func (l *Logger) Error(args ...interface{}) { l.log.Error(args...) }
+
+func (l *Logger) Debugf(format string, args ...interface{}) { l.log.Debugf(format, args...) }
+func (l *Logger) Infof(format string, args ...interface{})  { l.log.Infof(format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{})  { l.log.Warnf(format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) { l.log.Errorf(format, args...) }
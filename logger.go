@@ -0,0 +1,58 @@
+package services
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the logging facade used internally by Container. Implementing it
+// lets callers plug in any logging library without forcing a dependency on
+// logrus; Container defaults to a logrus-backed implementation so existing
+// callers keep working unchanged. See the slog and zap adapters in the
+// logadapter subpackages for alternatives.
+type Logger interface {
+	WithField(key string, value interface{}) Logger
+	WithError(err error) Logger
+
+	Debug(args ...interface{})
+	Info(args ...interface{})
+	Warn(args ...interface{})
+	Error(args ...interface{})
+
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// logrusLogger adapts *logrus.Entry to the Logger interface.
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+func newLogrusLogger() Logger {
+	return &logrusLogger{entry: logrus.NewEntry(logrus.StandardLogger())}
+}
+
+func (l *logrusLogger) WithField(key string, value interface{}) Logger {
+	return &logrusLogger{entry: l.entry.WithField(key, value)}
+}
+
+func (l *logrusLogger) WithError(err error) Logger {
+	return &logrusLogger{entry: l.entry.WithError(err)}
+}
+
+func (l *logrusLogger) Debug(args ...interface{}) { l.entry.Debug(args...) }
+func (l *logrusLogger) Info(args ...interface{})  { l.entry.Info(args...) }
+func (l *logrusLogger) Warn(args ...interface{})  { l.entry.Warn(args...) }
+func (l *logrusLogger) Error(args ...interface{}) { l.entry.Error(args...) }
+
+func (l *logrusLogger) Debugf(format string, args ...interface{}) { l.entry.Debugf(format, args...) }
+func (l *logrusLogger) Infof(format string, args ...interface{})  { l.entry.Infof(format, args...) }
+func (l *logrusLogger) Warnf(format string, args ...interface{})  { l.entry.Warnf(format, args...) }
+func (l *logrusLogger) Errorf(format string, args ...interface{}) { l.entry.Errorf(format, args...) }
+
+// SetLogger replaces the logger used by the container. It must be called
+// before StartAll to affect startup logging.
+func (c *Container) SetLogger(logger Logger) {
+	c.logger = logger
+}
@@ -0,0 +1,130 @@
+// Package metrics registers Prometheus collectors driven by a
+// services.Container's state-machine events. It is a separate package so
+// that pulling in Prometheus is opt-in and never forced on consumers of the
+// base services module.
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	services "github.com/Niondir/go-services"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector exposes per-service Prometheus collectors: a state gauge, init
+// and run duration histograms, a restart counter, an error counter, and a
+// gauge of currently-running services.
+type Collector struct {
+	state    *prometheus.GaugeVec
+	initDur  *prometheus.HistogramVec
+	runDur   *prometheus.HistogramVec
+	restarts *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	running  prometheus.Gauge
+
+	mu          sync.Mutex
+	initStarted map[string]time.Time
+	runStarted  map[string]time.Time
+}
+
+// WithMetrics registers a Collector's Prometheus collectors with registerer
+// and subscribes it to container's state-machine events, so it mirrors
+// container for as long as the process runs. Call it once per Container,
+// after registering all services and before StartAll: only transitions that
+// happen after it subscribes are observed, earlier ones are not replayed.
+func WithMetrics(container *services.Container, registerer prometheus.Registerer) (*Collector, error) {
+	c := &Collector{
+		state: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "services_service_state",
+			Help: "Current lifecycle state of each service (see services.ServiceState for the numeric encoding).",
+		}, []string{"service"}),
+		initDur: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "services_init_duration_seconds",
+			Help: "Duration of each service's Init() call.",
+		}, []string{"service"}),
+		runDur: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "services_run_duration_seconds",
+			Help: "Duration of each Run() attempt of a service.",
+		}, []string{"service"}),
+		restarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "services_restarts_total",
+			Help: "Number of times a service was restarted by its RestartPolicy.",
+		}, []string{"service"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "services_errors_total",
+			Help: "Number of times a service's Init() or Run() returned an error.",
+		}, []string{"service"}),
+		running: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "services_running",
+			Help: "Number of services currently in the Running state.",
+		}),
+		initStarted: map[string]time.Time{},
+		runStarted:  map[string]time.Time{},
+	}
+
+	collectors := []prometheus.Collector{c.state, c.initDur, c.runDur, c.restarts, c.errors, c.running}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	go c.watch(container.Subscribe())
+	return c, nil
+}
+
+func (c *Collector) watch(events <-chan services.StateEvent) {
+	for event := range events {
+		c.handle(event)
+	}
+}
+
+func (c *Collector) handle(event services.StateEvent) {
+	c.state.WithLabelValues(event.Service).Set(float64(event.To))
+
+	switch event.To {
+	case services.StateInitializing:
+		c.mu.Lock()
+		c.initStarted[event.Service] = event.Time
+		c.mu.Unlock()
+	case services.StateInitialized:
+		c.observeSince(c.initDur, c.initStarted, event)
+	case services.StateRunning:
+		c.mu.Lock()
+		c.runStarted[event.Service] = event.Time
+		c.mu.Unlock()
+		c.running.Inc()
+	case services.StateStarting:
+		if event.From == services.StateRunning {
+			c.restarts.WithLabelValues(event.Service).Inc()
+		}
+		// A restart after a failed Run carries that Run's error.
+		if event.Err != nil {
+			c.errors.WithLabelValues(event.Service).Inc()
+		}
+	case services.StateFailed:
+		c.errors.WithLabelValues(event.Service).Inc()
+	}
+
+	// Every transition away from Running, whether to a retry, a graceful
+	// stop, or a failure, closes out that run's duration and frees a slot
+	// in the running gauge.
+	if event.From == services.StateRunning && event.To != services.StateRunning {
+		c.observeSince(c.runDur, c.runStarted, event)
+		c.running.Dec()
+	}
+}
+
+func (c *Collector) observeSince(hist *prometheus.HistogramVec, started map[string]time.Time, event services.StateEvent) {
+	c.mu.Lock()
+	start, ok := started[event.Service]
+	if ok {
+		delete(started, event.Service)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	hist.WithLabelValues(event.Service).Observe(event.Time.Sub(start).Seconds())
+}
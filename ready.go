@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Readyer is an optional interface a service can implement to report when it
+// has finished starting up, e.g. once an HTTP server's Listen call returns.
+// When a service implements Readyer, runOne blocks until Ready returns
+// before the next dependency wave is allowed to start.
+type Readyer interface {
+	Ready(ctx context.Context) error
+}
+
+// ReadyFunc adapts a plain function to the Readyer interface.
+type ReadyFunc func(ctx context.Context) error
+
+func (f ReadyFunc) Ready(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ReadyNotifier is a helper services can embed or hold to implement Readyer.
+// The service calls SignalReady() once from inside its Run method, and the
+// container's wait for readiness unblocks.
+type ReadyNotifier struct {
+	once  sync.Once
+	ready chan struct{}
+}
+
+// NewReadyNotifier returns a ReadyNotifier ready to be embedded in a service.
+func NewReadyNotifier() *ReadyNotifier {
+	return &ReadyNotifier{ready: make(chan struct{})}
+}
+
+// SignalReady marks the service as ready. It is safe to call more than once.
+func (r *ReadyNotifier) SignalReady() {
+	r.once.Do(func() {
+		close(r.ready)
+	})
+}
+
+// Ready implements Readyer, blocking until SignalReady has been called or ctx
+// is done, whichever happens first.
+func (r *ReadyNotifier) Ready(ctx context.Context) error {
+	select {
+	case <-r.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitReady blocks until every registered service that implements Readyer
+// reports ready, or returns the first error encountered. Services that don't
+// implement Readyer are considered ready immediately.
+func (c *Container) WaitReady(ctx context.Context) error {
+	g, gctx := errgroup.WithContext(ctx)
+	for _, s := range c.services {
+		s := s
+		readyer, ok := s.service.(Readyer)
+		if !ok {
+			continue
+		}
+		g.Go(func() error {
+			return readyer.Ready(gctx)
+		})
+	}
+	return g.Wait()
+}
+
+// Health returns the current health of every registered service, keyed by
+// service name. A nil value means the service is healthy; a non-nil value is
+// either the error it failed with or an explanation that it never started.
+func (c *Container) Health() map[string]error {
+	health := make(map[string]error, len(c.services))
+	for _, s := range c.services {
+		rc, ok := c.getRunContext(s.name)
+		if !ok {
+			health[s.name] = fmt.Errorf("service '%s' has not been started", s.name)
+			continue
+		}
+
+		rc.mu.Lock()
+		state := rc.state
+		err := rc.err
+		rc.mu.Unlock()
+
+		switch state {
+		case StateFailed:
+			health[s.name] = err
+		case StateSkipped:
+			health[s.name] = fmt.Errorf("service '%s' was skipped and never started", s.name)
+		default:
+			health[s.name] = nil
+		}
+	}
+	return health
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestartMode controls whether and when a failed or finished service is
+// restarted by its supervising goroutine.
+type RestartMode int
+
+const (
+	// RestartNever never restarts the service; this is the zero value, so a
+	// service without an explicit RestartPolicy behaves exactly as before.
+	RestartNever RestartMode = iota
+	// RestartOnFailure restarts the service only when Run returns a non-nil error.
+	RestartOnFailure
+	// RestartAlways restarts the service whenever Run returns, even with a nil error.
+	RestartAlways
+)
+
+// BackoffMode controls how the delay between restart attempts grows.
+type BackoffMode int
+
+const (
+	// BackoffConstant waits InitialDelay before every restart attempt.
+	BackoffConstant BackoffMode = iota
+	// BackoffExponential doubles the delay after each attempt, up to MaxDelay.
+	BackoffExponential
+)
+
+// RestartPolicy configures whether and how a service is restarted after Run
+// returns. The zero value is RestartNever, matching the container's original
+// behavior of never restarting a stopped service.
+type RestartPolicy struct {
+	Mode RestartMode
+	// MaxRetries caps the number of restart attempts. A value <= 0 means
+	// unlimited retries.
+	MaxRetries int
+	Backoff    BackoffMode
+	// InitialDelay is the delay before the first restart attempt, and the
+	// constant delay for BackoffConstant.
+	InitialDelay time.Duration
+	// MaxDelay caps the delay computed for BackoffExponential. A value <= 0
+	// means unlimited.
+	MaxDelay time.Duration
+	// ResetAfter, when a Run attempt lasted at least this long, resets the
+	// attempt/backoff counter as if the service were restarting fresh.
+	ResetAfter time.Duration
+}
+
+// PolicyProvider is an optional interface a service can implement to supply
+// its own RestartPolicy instead of RegisterWithPolicy. When both are present,
+// the policy given to RegisterWithPolicy is used and Policy() is ignored.
+type PolicyProvider interface {
+	Policy() RestartPolicy
+}
+
+// RegisterWithPolicy registers service the same way Register does, but also
+// gives it a RestartPolicy that runOne's supervisor loop uses to decide
+// whether to restart the service after Run returns.
+func (c *Container) RegisterWithPolicy(service Runner, policy RestartPolicy) {
+	c.Register(service)
+	info := c.serviceByName(serviceName(service))
+	info.policy = &policy
+}
+
+// resolvePolicy returns the RestartPolicy for s, preferring an explicit
+// RegisterWithPolicy call over the PolicyProvider interface, defaulting to
+// RestartNever.
+func (c *Container) resolvePolicy(s *serviceInfo) RestartPolicy {
+	if s.policy != nil {
+		return *s.policy
+	}
+	if pp, ok := s.service.(PolicyProvider); ok {
+		return pp.Policy()
+	}
+	return RestartPolicy{}
+}
+
+// shouldRestart decides whether, after the given attempt, the service should
+// be restarted according to policy.
+func shouldRestart(policy RestartPolicy, runErr error, attempt int) bool {
+	switch policy.Mode {
+	case RestartAlways:
+	case RestartOnFailure:
+		if runErr == nil {
+			return false
+		}
+	default:
+		return false
+	}
+	if policy.MaxRetries > 0 && attempt > policy.MaxRetries {
+		return false
+	}
+	return true
+}
+
+// backoffDelay computes how long to wait before the given attempt number
+// (the attempt that is about to be retried) according to policy.
+func backoffDelay(policy RestartPolicy, attempt int) time.Duration {
+	delay := policy.InitialDelay
+	if policy.Backoff == BackoffExponential {
+		for i := 1; i < attempt; i++ {
+			delay *= 2
+			if policy.MaxDelay > 0 && delay >= policy.MaxDelay {
+				delay = policy.MaxDelay
+				break
+			}
+		}
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
+
+// superviseRun runs s.service.Run, restarting it according to its
+// RestartPolicy, until Run should not be restarted anymore or runner.ctx is
+// cancelled. It returns the error of the last Run attempt.
+func (c *Container) superviseRun(s *serviceInfo, runner *runContext) error {
+	policy := c.resolvePolicy(s)
+	logger := c.logger.WithField("service", s.name)
+
+	var runErr error
+	for attempt := 1; ; {
+		runner.mu.Lock()
+		runner.attempts = attempt
+		runner.mu.Unlock()
+
+		start := time.Now()
+		runErr = s.service.Run(runner.ctx)
+
+		runner.mu.Lock()
+		runner.err = runErr
+		runner.mu.Unlock()
+
+		if runner.ctx.Err() != nil {
+			// The service is being stopped deliberately; don't restart it.
+			return runErr
+		}
+
+		if policy.ResetAfter > 0 && time.Since(start) >= policy.ResetAfter {
+			// The service ran long enough to be considered healthy again;
+			// restart the attempt/backoff count as if this were attempt 1,
+			// before shouldRestart sees it, so a long-lived run actually
+			// resets the retry budget instead of still counting against it.
+			attempt = 0
+			runner.mu.Lock()
+			runner.attempts = 0
+			runner.mu.Unlock()
+		}
+
+		if !shouldRestart(policy, runErr, attempt) {
+			return runErr
+		}
+
+		delay := backoffDelay(policy, attempt)
+		logger.WithError(runErr).Warnf("Service stopped, restarting attempt %d in %s", attempt+1, delay)
+		c.transition(runner, StateStarting, runErr)
+
+		select {
+		case <-time.After(delay):
+		case <-runner.ctx.Done():
+			return runErr
+		}
+		c.transition(runner, StateRunning, nil)
+		attempt++
+	}
+}
+
+// ServiceStatus reports a service's current lifecycle state together with
+// its restart attempt count and last error, if any.
+type ServiceStatus struct {
+	State    ServiceState
+	Attempts int
+	LastErr  error
+}
+
+// ServiceStatus returns the current status of the named service.
+func (c *Container) ServiceStatus(name string) (ServiceStatus, error) {
+	rc, ok := c.getRunContext(name)
+	if !ok {
+		return ServiceStatus{}, fmt.Errorf("service '%s' has not been started", name)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return ServiceStatus{
+		State:    rc.state,
+		Attempts: rc.attempts,
+		LastErr:  rc.err,
+	}, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShouldRestart(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	cases := []struct {
+		name    string
+		policy  RestartPolicy
+		runErr  error
+		attempt int
+		want    bool
+	}{
+		{
+			name:    "RestartNever never restarts",
+			policy:  RestartPolicy{Mode: RestartNever},
+			runErr:  errBoom,
+			attempt: 1,
+			want:    false,
+		},
+		{
+			name:    "RestartOnFailure does not restart a nil error",
+			policy:  RestartPolicy{Mode: RestartOnFailure},
+			runErr:  nil,
+			attempt: 1,
+			want:    false,
+		},
+		{
+			name:    "RestartOnFailure restarts a failure",
+			policy:  RestartPolicy{Mode: RestartOnFailure},
+			runErr:  errBoom,
+			attempt: 1,
+			want:    true,
+		},
+		{
+			name:    "RestartAlways restarts even a nil error",
+			policy:  RestartPolicy{Mode: RestartAlways},
+			runErr:  nil,
+			attempt: 1,
+			want:    true,
+		},
+		{
+			// attempt is 1 on the initial, non-retry run, so MaxRetries: 1
+			// must still allow that first restart.
+			name:    "MaxRetries 1 allows the first restart",
+			policy:  RestartPolicy{Mode: RestartOnFailure, MaxRetries: 1},
+			runErr:  errBoom,
+			attempt: 1,
+			want:    true,
+		},
+		{
+			name:    "MaxRetries 1 denies the second restart",
+			policy:  RestartPolicy{Mode: RestartOnFailure, MaxRetries: 1},
+			runErr:  errBoom,
+			attempt: 2,
+			want:    false,
+		},
+		{
+			name:    "MaxRetries 3 allows the third restart",
+			policy:  RestartPolicy{Mode: RestartOnFailure, MaxRetries: 3},
+			runErr:  errBoom,
+			attempt: 3,
+			want:    true,
+		},
+		{
+			name:    "MaxRetries 3 denies the fourth restart",
+			policy:  RestartPolicy{Mode: RestartOnFailure, MaxRetries: 3},
+			runErr:  errBoom,
+			attempt: 4,
+			want:    false,
+		},
+		{
+			name:    "MaxRetries 0 means unlimited",
+			policy:  RestartPolicy{Mode: RestartOnFailure, MaxRetries: 0},
+			runErr:  errBoom,
+			attempt: 1000,
+			want:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shouldRestart(tc.policy, tc.runErr, tc.attempt); got != tc.want {
+				t.Errorf("shouldRestart(%+v, %v, %d) = %v, want %v", tc.policy, tc.runErr, tc.attempt, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestRestartPolicyHonorsMaxRetries exercises shouldRestart through the real
+// superviseRun loop: MaxRetries: 2 should yield exactly one initial run plus
+// two restarts, not one plus one.
+func TestRestartPolicyHonorsMaxRetries(t *testing.T) {
+	c := NewContainer()
+
+	var attempts int32
+	svc := FuncService(func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("always fails")
+	})
+	c.RegisterWithPolicy(svc, RestartPolicy{
+		Mode:         RestartOnFailure,
+		MaxRetries:   2,
+		InitialDelay: time.Millisecond,
+	})
+
+	if err := c.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&attempts) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// Give superviseRun a moment to decide there's no further attempt.
+	time.Sleep(20 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("expected exactly 3 attempts (1 initial + 2 retries) for MaxRetries: 2, got %d", got)
+	}
+}
@@ -8,12 +8,18 @@
 // All services have to implement the Runner interface. Run() is blocking and only returns when the service stops working.
 //
 // All services inside one container are started and stopped together. If one service fails, all are stopped.
+//
+// Services can declare start-order dependencies on other services via Container.RegisterWithDeps
+// or by implementing DependencyProvider. StartAll groups services into waves based on these
+// dependencies and starts each wave concurrently, only moving to the next wave once every
+// service in the current one has been initialized and its Run has been launched. StopAll
+// reverses this order, stopping dependents before the services they depend on.
 package services
 
 import (
 	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 	"strings"
 	"sync"
 	"time"
@@ -41,22 +47,72 @@ func (sr *startRunner) String() string {
 
 type runContext struct {
 	service *serviceInfo
-	running bool
 	done    chan error
-	err     error
+	// stopped is closed once, by whichever Wait() caller first drains done,
+	// so Wait() is safe to call concurrently (e.g. from StopAll and
+	// WaitAllStopped at the same time) and repeatedly.
+	stopped  chan struct{}
+	waitOnce sync.Once
+	// execDone is closed when the Run goroutine returns, letting a pending
+	// readiness wait in runOne bail out if the service exits before it ever
+	// signals ready.
+	execDone chan struct{}
+	// ctx and cancel scope this service's Run call. They are derived from
+	// the container's runCtx so that StopAll can stop dependents without
+	// also cancelling the dependencies they still rely on.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// mu guards running, state, attempts and err, all of which are read and
+	// written concurrently: running/err by the Run goroutine and Wait(),
+	// state/attempts by the supervisor loop in superviseRun.
+	mu       sync.Mutex
+	running  bool
+	state    ServiceState
+	attempts int
+	err      error
+}
+
+// setRunning updates running under rc.mu.
+func (rc *runContext) setRunning(v bool) {
+	rc.mu.Lock()
+	rc.running = v
+	rc.mu.Unlock()
+}
+
+// isRunning reads running under rc.mu.
+func (rc *runContext) isRunning() bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.running
 }
 
 type serviceInfo struct {
 	name    string
 	service Runner
+	// deps holds the names of services this service depends on, populated
+	// via RegisterWithDeps and/or the DependencyProvider interface.
+	deps []string
+	// policy is set by RegisterWithPolicy; if nil, resolvePolicy falls back
+	// to the PolicyProvider interface and then to RestartNever.
+	policy *RestartPolicy
 }
 
+// Wait blocks until the service has stopped. It is safe to call concurrently
+// and more than once: only the first caller drains done, every caller blocks
+// until that happens.
 func (rc *runContext) Wait() {
-	if !rc.running {
+	if !rc.isRunning() {
 		return
 	}
-	rc.err = <-rc.done
-	rc.running = false
+	rc.waitOnce.Do(func() {
+		err := <-rc.done
+		rc.mu.Lock()
+		rc.err = err
+		rc.running = false
+		rc.mu.Unlock()
+		close(rc.stopped)
+	})
+	<-rc.stopped
 }
 
 // Container with all services
@@ -70,14 +126,27 @@ type Container struct {
 	runCtx context.Context
 	// Cancel method of the runCtx
 	runCtxCancel context.CancelFunc
-	services     []*serviceInfo
-	runContexts  map[string]*runContext
+	services []*serviceInfo
+	// runContextsMu guards runContexts: StartAll runs a wave's services
+	// concurrently, and each one's initOne inserts into this map.
+	runContextsMu sync.Mutex
+	runContexts   map[string]*runContext
+	// waves is the dependency-ordered start plan computed by StartAll and
+	// reused by StopAll to shut services down in reverse order.
+	waves [][]*serviceInfo
+	// subsMu guards subscribers, the list of channels fed by Subscribe.
+	subsMu      sync.Mutex
+	subscribers []chan StateEvent
+	// logger is used for all internal logging. Defaults to a logrus adapter
+	// for backward compatibility; override it with SetLogger.
+	logger Logger
 }
 
 func NewContainer() *Container {
 	return &Container{
 		services:    make([]*serviceInfo, 0),
 		runContexts: map[string]*runContext{},
+		logger:      newLogrusLogger(),
 	}
 }
 
@@ -90,12 +159,19 @@ func Default() *Container {
 	return defaultContainer
 }
 
-// Register adds a service to the list of services to be initialized
-func (c *Container) Register(service Runner) {
+// serviceName derives the registration name of a service, preferring
+// fmt.Stringer over the default %T representation.
+func serviceName(service Runner) string {
 	name := fmt.Sprintf("%T", service)
 	if s, ok := service.(fmt.Stringer); ok {
 		name = s.String()
 	}
+	return name
+}
+
+// Register adds a service to the list of services to be initialized
+func (c *Container) Register(service Runner) {
+	name := serviceName(service)
 
 	for _, s := range c.services {
 		if s.name == name {
@@ -116,27 +192,63 @@ func (f FuncService) Run(ctx context.Context) error {
 	return f(ctx)
 }
 
-func newRunContext(s *serviceInfo) *runContext {
+// getRunContext returns the runContext registered for name, if any.
+func (c *Container) getRunContext(name string) (*runContext, bool) {
+	c.runContextsMu.Lock()
+	defer c.runContextsMu.Unlock()
+	rc, ok := c.runContexts[name]
+	return rc, ok
+}
+
+// setRunContext registers rc as the runContext for name.
+func (c *Container) setRunContext(name string, rc *runContext) {
+	c.runContextsMu.Lock()
+	c.runContexts[name] = rc
+	c.runContextsMu.Unlock()
+}
+
+// allRunContexts returns a snapshot of every runContext registered so far.
+func (c *Container) allRunContexts() map[string]*runContext {
+	c.runContextsMu.Lock()
+	defer c.runContextsMu.Unlock()
+	snapshot := make(map[string]*runContext, len(c.runContexts))
+	for k, v := range c.runContexts {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+func newRunContext(ctx context.Context, s *serviceInfo) *runContext {
+	runCtx, cancel := context.WithCancel(ctx)
 	return &runContext{
-		service: s,
-		done:    make(chan error, 1),
+		service:  s,
+		done:     make(chan error, 1),
+		stopped:  make(chan struct{}),
+		execDone: make(chan struct{}),
+		ctx:      runCtx,
+		cancel:   cancel,
 	}
 }
 
 func (c *Container) initOne(ctx context.Context, s *serviceInfo) error {
-	logger := logrus.WithField("service", s.name)
-	runner := newRunContext(s)
-	if _, ok := c.runContexts[s.name]; ok {
+	logger := c.logger.WithField("service", s.name)
+	if _, ok := c.getRunContext(s.name); ok {
 		return fmt.Errorf("service '%s' already started", s.name)
 	}
 
-	c.runContexts[s.name] = runner
+	runner := newRunContext(ctx, s)
+	c.setRunContext(s.name, runner)
+	c.transition(runner, StateInitializing, nil)
 
 	// Execute initialization code if any
 	if starter, ok := s.service.(Initer); ok {
 		logger.Info("Execute service.Init()")
 		err := starter.Init(ctx)
 		if err != nil {
+			runner.mu.Lock()
+			runner.err = err
+			runner.mu.Unlock()
+			c.transition(runner, StateFailed, err)
 			go func() {
 				// Let the runner stop immediately
 				// The error is nil, since it is the "Run()" error
@@ -146,35 +258,72 @@ func (c *Container) initOne(ctx context.Context, s *serviceInfo) error {
 		}
 	}
 
+	c.transition(runner, StateInitialized, nil)
 	return nil
 }
 
-func (c *Container) runOne(ctx context.Context, s *serviceInfo) error {
-	logger := logrus.WithField("service", s.name)
+func (c *Container) runOne(s *serviceInfo) error {
+	logger := c.logger.WithField("service", s.name)
 
-	runner, ok := c.runContexts[s.name]
+	runner, ok := c.getRunContext(s.name)
 	if !ok {
 		return fmt.Errorf("service '%s' not initialized", s.name)
 	}
-	if runner.running {
+	if runner.isRunning() {
 		return fmt.Errorf("service '%s' already running", s.name)
 	}
 
 	// Execute the actual run method in background
-	runner.running = true
+	runner.setRunning(true)
+	c.transition(runner, StateStarting, nil)
+	entered := make(chan struct{})
 	go func() {
+		defer close(runner.execDone)
 		logger.Info("Execute service.Run()")
-		runErr := s.service.Run(ctx)
+		close(entered)
+		runErr := c.superviseRun(s, runner)
 		runner.done <- runErr
 		if runErr != nil {
-			// TODO: Make this optional / configurable?
-			logger.WithError(runErr).Error("Service stopped with error. Stop all services.")
-			c.StopAll()
+			c.transition(runner, StateFailed, runErr)
+			if runner.ctx.Err() == nil {
+				// Restarts (if any) are exhausted and the container wasn't
+				// already stopping this service; escalate.
+				// TODO: Make this optional / configurable?
+				logger.WithError(runErr).Error("Service stopped with error. Stop all services.")
+				c.StopAll(context.Background())
+			} else {
+				logger.WithError(runErr).Warn("Service stopped with error during shutdown")
+			}
 		} else {
+			c.transition(runner, StateFinished, nil)
 			logger.Error("Service stopped")
 		}
 	}()
+	// Don't report the service as started before its Run goroutine is
+	// actually executing.
+	<-entered
+
+	// If the service reports its own readiness, wait for it (or for the
+	// service to exit first) before letting dependent waves start.
+	if readyer, ok := s.service.(Readyer); ok {
+		readyErr := make(chan error, 1)
+		go func() {
+			readyErr <- readyer.Ready(runner.ctx)
+		}()
+		select {
+		case err := <-readyErr:
+			if err != nil {
+				return fmt.Errorf("service '%s' failed ready check: %w", s.name, err)
+			}
+		case <-runner.execDone:
+			// Service already exited before ever signalling ready; the Run
+			// goroutine has already moved it to its final state, so don't
+			// overwrite that with StateRunning.
+			return nil
+		}
+	}
 
+	c.transition(runner, StateRunning, nil)
 	return nil
 }
 
@@ -184,53 +333,118 @@ func (c *Container) StartAll(ctx context.Context) error {
 	}
 	c.runCtx, c.runCtxCancel = context.WithCancel(ctx)
 
-	// Iterate over all services to initialize them
-	for i := range c.services {
-		s := c.services[i]
-		logger := logrus.WithField("service", s.name)
-		// TODO: Should we allow services to optionally initialize in parallel?
-		logger.Infof("Initialize service %d/%d", i+1, len(c.services))
-
-		err := c.initOne(c.runCtx, s)
-		if err != nil {
-			logger.Errorf("Failed to initialize service.")
-			c.runCtxCancel()
-			return err
-		}
+	waves, err := c.buildWaves()
+	if err != nil {
+		c.skipRemaining([][]*serviceInfo{c.services}, 0)
+		c.runCtxCancel()
+		return err
 	}
-
-	// Iterate over all services to run them
-	for i := range c.services {
-		s := c.services[i]
-		logger := logrus.WithField("service", s.name)
-		logger.Infof("Run service %d/%d", i+1, len(c.services))
-
-		err := c.runOne(c.runCtx, s)
-		if err != nil {
-			logger.WithError(err).Errorf("Failed to start service.")
+	c.waves = waves
+
+	// Initialize and run each wave. Services within a wave are independent
+	// of each other and are started concurrently; the next wave only starts
+	// once every service in the current wave has finished Init and entered
+	// Run.
+	for waveIdx, wave := range waves {
+		names := make([]string, len(wave))
+		for i, s := range wave {
+			names[i] = s.name
+		}
+		c.logger.WithField("services", strings.Join(names, ",")).
+			Infof("Starting wave %d/%d", waveIdx+1, len(waves))
+
+		g, _ := errgroup.WithContext(c.runCtx)
+		for _, s := range wave {
+			s := s
+			g.Go(func() error {
+				logger := c.logger.WithField("service", s.name)
+				logger.Info("Initialize service")
+				if err := c.initOne(c.runCtx, s); err != nil {
+					return err
+				}
+				logger.Info("Start service")
+				return c.runOne(s)
+			})
+		}
+		if err := g.Wait(); err != nil {
+			c.logger.WithError(err).Errorf("Failed to start wave %d/%d", waveIdx+1, len(waves))
+			c.skipRemaining(waves, waveIdx+1)
 			c.runCtxCancel()
 			return err
 		}
 	}
 
-	logrus.Info("All services running")
+	c.logger.Info("All services running")
 	return nil
 }
 
-// StopAll gracefully stops all services.
-// If you need a timeout, passe a context with Timeout or Deadline
-func (c *Container) StopAll() {
+// StopAll gracefully stops all services, reversing the dependency order
+// computed by StartAll: services in the last wave are cancelled and waited
+// for before services in the waves they depend on. Each service gets its
+// Stop hook (if any) called and is then waited on, all bounded by ctx's
+// deadline; a service that doesn't stop in time is marked StateForceKilled
+// and StopAll moves on without it. Pass a context with a Timeout or Deadline
+// to enforce one.
+func (c *Container) StopAll(ctx context.Context) {
 	if c.runCtxCancel == nil {
 		panic("call Container.StartAll() before StopAll()")
 	}
+
+	for i := len(c.waves) - 1; i >= 0; i-- {
+		wg := sync.WaitGroup{}
+		for _, s := range c.waves[i] {
+			runner, ok := c.getRunContext(s.name)
+			if !ok || !runner.isRunning() {
+				continue
+			}
+			wg.Add(1)
+			go func(s *serviceInfo, runner *runContext) {
+				defer wg.Done()
+				c.stopOne(ctx, s, runner)
+			}(s, runner)
+		}
+		wg.Wait()
+	}
+
 	c.runCtxCancel()
 }
 
+// stopOne runs s's Stop hook (if any), cancels its run context, and waits
+// for Run to return, all bounded by ctx's deadline. If the deadline elapses
+// before Run returns, the service is marked StateForceKilled and stopOne
+// returns without waiting any further; the Run goroutine may still be
+// running in the background.
+func (c *Container) stopOne(ctx context.Context, s *serviceInfo, runner *runContext) {
+	logger := c.logger.WithField("service", s.name)
+	c.transition(runner, StateStopping, nil)
+
+	if stopper, ok := s.service.(Stopper); ok {
+		logger.Info("Execute service.Stop()")
+		if err := stopper.Stop(ctx); err != nil {
+			logger.WithError(err).Warn("Service.Stop() returned an error")
+		}
+	}
+
+	runner.cancel()
+
+	waited := make(chan struct{})
+	go func() {
+		runner.Wait()
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+	case <-ctx.Done():
+		logger.Warn("Service did not stop before the shutdown deadline; giving up on it")
+		c.transition(runner, StateForceKilled, ctx.Err())
+	}
+}
+
 func (c *Container) runningServices() []*runContext {
 	rcs := make([]*runContext, 0)
-	for i := range c.runContexts {
-		rc := c.runContexts[i]
-		if rc.running {
+	for _, rc := range c.allRunContexts() {
+		if rc.isRunning() {
 			rcs = append(rcs, rc)
 		}
 	}
@@ -241,17 +455,21 @@ func (c *Container) runningServices() []*runContext {
 func (c *Container) WaitAllStopped(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 
+	runContexts := c.allRunContexts()
 	wg := sync.WaitGroup{}
-	logrus.WithField("count", len(c.runContexts)).Infof("Wait till all services are stopped")
-	wg.Add(len(c.runContexts))
-	for k := range c.runContexts {
-		rc := c.runContexts[k]
-		logger := logrus.WithField("service", rc.service.name)
+	c.logger.WithField("count", len(runContexts)).Infof("Wait till all services are stopped")
+	wg.Add(len(runContexts))
+	for _, rc := range runContexts {
+		rc := rc
+		logger := c.logger.WithField("service", rc.service.name)
 		go func() {
 			logger.Info("Stopping service")
 			rc.Wait()
-			if rc.err != nil {
-				logger.WithError(rc.err).Warn("Service stopped with error")
+			rc.mu.Lock()
+			err := rc.err
+			rc.mu.Unlock()
+			if err != nil {
+				logger.WithError(err).Warn("Service stopped with error")
 			}
 
 			wg.Done()
@@ -286,21 +504,24 @@ func (c *Container) WaitAllStopped(ctx context.Context) {
 // ServiceErrors returns all errors occured in services
 func (c *Container) ServiceErrors() map[string]error {
 	errs := map[string]error{}
-	for _, rc := range c.runContexts {
-		if rc.err != nil {
-			errs[rc.service.name] = rc.err
+	for _, rc := range c.allRunContexts() {
+		rc.mu.Lock()
+		err := rc.err
+		rc.mu.Unlock()
+		if err != nil {
+			errs[rc.service.name] = err
 		}
 	}
 	return errs
 }
 
-func (c *Container) runningServicesLogger() *logrus.Entry {
+func (c *Container) runningServicesLogger() Logger {
 	rcs := c.runningServices()
 	names := make([]string, len(rcs))
 	for i := range rcs {
 		names[i] = rcs[i].service.name
 	}
 	namesJoined := strings.Join(names, ",")
-	return logrus.WithField("count", len(rcs)).
+	return c.logger.WithField("count", len(rcs)).
 		WithField("services", namesJoined)
 }
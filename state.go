@@ -0,0 +1,165 @@
+package services
+
+import (
+	"time"
+)
+
+// ServiceState describes where a service currently is in its lifecycle.
+type ServiceState int
+
+const (
+	// StateRegistered is the initial state of every service once Register
+	// or RegisterWithDeps returns.
+	StateRegistered ServiceState = iota
+	// StateInitializing means Init() has been called and has not returned yet.
+	StateInitializing
+	// StateInitialized means Init() returned successfully (or the service
+	// does not implement Initer).
+	StateInitialized
+	// StateStarting means the Run() goroutine has been launched but has not
+	// been confirmed to have started executing yet.
+	StateStarting
+	// StateRunning means Run() is confirmed to be executing.
+	StateRunning
+	// StateStopping means the service's context has been cancelled and we
+	// are waiting for Run() to return.
+	StateStopping
+	// StateFinished means Run() returned nil.
+	StateFinished
+	// StateFailed means Init() or Run() returned an error.
+	StateFailed
+	// StateSkipped means the service never started because an earlier wave
+	// failed to start or a dependency cycle was detected.
+	StateSkipped
+	// StateForceKilled means the service did not stop (Stop/Run did not
+	// return) before StopAll's deadline elapsed. The Run goroutine, if any,
+	// is still running in the background.
+	StateForceKilled
+)
+
+func (s ServiceState) String() string {
+	switch s {
+	case StateRegistered:
+		return "Registered"
+	case StateInitializing:
+		return "Initializing"
+	case StateInitialized:
+		return "Initialized"
+	case StateStarting:
+		return "Starting"
+	case StateRunning:
+		return "Running"
+	case StateStopping:
+		return "Stopping"
+	case StateFinished:
+		return "Finished"
+	case StateFailed:
+		return "Failed"
+	case StateSkipped:
+		return "Skipped"
+	case StateForceKilled:
+		return "ForceKilled"
+	default:
+		return "Unknown"
+	}
+}
+
+// StateEvent is emitted on every state transition of a service.
+type StateEvent struct {
+	Service string
+	From    ServiceState
+	To      ServiceState
+	Time    time.Time
+	Err     error
+}
+
+// stateSubscriberBuffer is the channel buffer size for Subscribe. Transitions
+// are delivered best-effort: a subscriber that falls behind misses events
+// rather than blocking the container.
+const stateSubscriberBuffer = 64
+
+// Subscribe returns a channel that receives a StateEvent for every state
+// transition of every service registered in the container. The channel is
+// never closed by the container.
+func (c *Container) Subscribe() <-chan StateEvent {
+	ch := make(chan StateEvent, stateSubscriberBuffer)
+	c.subsMu.Lock()
+	c.subscribers = append(c.subscribers, ch)
+	c.subsMu.Unlock()
+	return ch
+}
+
+func (c *Container) publish(event StateEvent) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	for _, sub := range c.subscribers {
+		select {
+		case sub <- event:
+		default:
+			// Subscriber is too slow / not draining; drop the event rather
+			// than block state transitions.
+		}
+	}
+}
+
+// State returns the current lifecycle state of the named service. Unknown
+// service names return StateRegistered's zero value.
+func (c *Container) State(name string) ServiceState {
+	if rc, ok := c.getRunContext(name); ok {
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		return rc.state
+	}
+	return StateRegistered
+}
+
+// terminal reports whether s is a state a service reaches once its Run
+// goroutine has returned for good and will never restart from.
+func (s ServiceState) terminal() bool {
+	switch s {
+	case StateFinished, StateFailed, StateSkipped, StateForceKilled:
+		return true
+	default:
+		return false
+	}
+}
+
+// transition moves a runContext to a new state and publishes a StateEvent
+// to all subscribers. A transition to StateRunning or StateStopping is
+// dropped if the service already reached a terminal state (e.g. its Run
+// goroutine returned before runOne got around to marking it Running, or it
+// failed and then triggered StopAll itself), so a service that exits
+// instantly never gets reported as still running or stopping.
+func (c *Container) transition(rc *runContext, to ServiceState, err error) {
+	rc.mu.Lock()
+	from := rc.state
+	if (to == StateRunning || to == StateStopping) && from.terminal() {
+		rc.mu.Unlock()
+		return
+	}
+	rc.state = to
+	rc.mu.Unlock()
+
+	c.publish(StateEvent{
+		Service: rc.service.name,
+		From:    from,
+		To:      to,
+		Time:    time.Now(),
+		Err:     err,
+	})
+}
+
+// skipRemaining marks every service in waves[from:] that never got a
+// runContext as StateSkipped, used when startup aborts partway through.
+func (c *Container) skipRemaining(waves [][]*serviceInfo, from int) {
+	for _, wave := range waves[from:] {
+		for _, s := range wave {
+			if _, ok := c.getRunContext(s.name); ok {
+				continue
+			}
+			runner := newRunContext(c.runCtx, s)
+			c.setRunContext(s.name, runner)
+			c.transition(runner, StateSkipped, nil)
+		}
+	}
+}
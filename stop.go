@@ -0,0 +1,12 @@
+package services
+
+import "context"
+
+// Stopper is an optional interface a service can implement to run cleanup
+// logic before its Run context is cancelled, e.g. to drain in-flight
+// requests. StopAll calls Stop before cancelling the service's context, and
+// enforces ctx's deadline on both Stop and the subsequent wait for Run to
+// return.
+type Stopper interface {
+	Stop(ctx context.Context) error
+}